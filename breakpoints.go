@@ -0,0 +1,59 @@
+package golldb
+
+import "fmt"
+
+// BreakpointType identifies the kind of stop condition a Z/z packet
+// installs.
+type BreakpointType int
+
+const (
+	BreakpointSoftware BreakpointType = iota
+	BreakpointHardware
+	BreakpointWrite
+	BreakpointRead
+	BreakpointAccess
+)
+
+// BreakpointKind describes a breakpoint or watchpoint to install: its type
+// (software/hardware/watchpoint variant) and the instruction length in
+// bytes, which matters on architectures with mixed-width instructions such
+// as ARM/Thumb.
+type BreakpointKind struct {
+	Type   BreakpointType
+	Length int
+}
+
+// ztype returns the Z/z packet type digit for kind.
+func (k BreakpointKind) ztype() int {
+	switch k.Type {
+	case BreakpointHardware:
+		return 1
+	case BreakpointWrite:
+		return 2
+	case BreakpointRead:
+		return 3
+	case BreakpointAccess:
+		return 4
+	default:
+		return 0
+	}
+}
+
+// SetBreakpoint inserts a breakpoint or watchpoint of the given kind at
+// addr, via a Z packet. Z0/Z1 (software/hardware breakpoints) predate
+// qSupported and are effectively universal, so no capability gate is
+// applied here; swbreak/hwbreak in qSupported only describe stop-reply
+// annotations, not Z/z support.
+func (l *LLDB) SetBreakpoint(addr *Address, kind BreakpointKind) error {
+	msg := fmt.Sprintf("Z%d,%x,%x", kind.ztype(), addr.value, kind.Length)
+	_, err := l.execSimple(msg)
+	return err
+}
+
+// ClearBreakpoint removes a previously set breakpoint or watchpoint of the
+// given kind at addr, via a z packet.
+func (l *LLDB) ClearBreakpoint(addr *Address, kind BreakpointKind) error {
+	msg := fmt.Sprintf("z%d,%x,%x", kind.ztype(), addr.value, kind.Length)
+	_, err := l.execSimple(msg)
+	return err
+}
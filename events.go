@@ -0,0 +1,195 @@
+package golldb
+
+import (
+	"encoding/hex"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// StopEvent describes a stop-reply packet (T/S/W/X) the stub sends after the
+// inferior stops or exits.
+type StopEvent struct {
+	Signal         uint8
+	ThreadID       string
+	Reason         string
+	Registers      map[string]uint64
+	WatchpointAddr *Address
+	ExitStatus     *int
+	// Threads lists every live thread ID, populated from the stop-reply's
+	// "threads" field when the stub honored QListThreadsInStopReply.
+	Threads []string
+}
+
+// Events returns the channel on which StopEvent values are published as the
+// stub reports the target stopping.
+func (l *LLDB) Events() <-chan StopEvent {
+	return l.events
+}
+
+// SetStdoutWriter sets the writer that decoded O-packet (inferior stdout)
+// bytes are forwarded to while the target is running. Pair with SetStdout
+// when the stub is configured to pipe the inferior's output back to us
+// instead of a file.
+func (l *LLDB) SetStdoutWriter(w io.Writer) {
+	l.stdoutWriter = w
+}
+
+// runUntilStop reads packets from the stub until it observes a stop-reply
+// (T/S/W/X), forwarding any O-packets (inferior stdout) along the way. It is
+// started in its own goroutine by Run/Continue.
+func (l *LLDB) runUntilStop() {
+	for {
+		raw, err := l.recvPacket()
+		if err != nil {
+			return
+		}
+		if len(raw) == 0 {
+			continue
+		}
+
+		switch raw[0] {
+		case 'O':
+			l.writeStdout(raw[1:])
+		case 'T', 'S', 'W', 'X':
+			l.publishStop(parseStopReply(raw))
+			return
+		}
+	}
+}
+
+// writeStdout hex-decodes an O-packet payload and forwards it to the
+// configured stdout writer, if any.
+func (l *LLDB) writeStdout(hexPayload []byte) {
+	if l.stdoutWriter == nil {
+		return
+	}
+	decoded, err := hex.DecodeString(string(hexPayload))
+	if err != nil {
+		return
+	}
+	l.stdoutWriter.Write(decoded)
+}
+
+// publishStop wakes up a pending Interrupt (if any) and then delivers ev on
+// the events channel.
+func (l *LLDB) publishStop(ev StopEvent) {
+	l.Lock()
+	wait := l.waitStop
+	l.waitStop = nil
+	l.Unlock()
+
+	if wait != nil {
+		close(wait)
+	}
+
+	l.events <- ev
+}
+
+// parseStopReply decodes a T/S/W/X stop-reply packet into a StopEvent.
+func parseStopReply(raw []byte) StopEvent {
+	s := string(raw)
+	ev := StopEvent{Registers: make(map[string]uint64)}
+
+	switch s[0] {
+	case 'W', 'X':
+		ev.Reason = "exited"
+		if len(s) >= 3 {
+			if n, err := strconv.ParseInt(s[1:3], 16, 32); err == nil {
+				status := int(n)
+				ev.ExitStatus = &status
+			}
+		}
+		return ev
+	case 'S':
+		if len(s) >= 3 {
+			if n, err := strconv.ParseUint(s[1:3], 16, 8); err == nil {
+				ev.Signal = uint8(n)
+			}
+		}
+		return ev
+	}
+
+	if len(s) < 3 {
+		ev.Reason = "signal"
+		return ev
+	}
+
+	if n, err := strconv.ParseUint(s[1:3], 16, 8); err == nil {
+		ev.Signal = uint8(n)
+	}
+
+	for _, field := range strings.Split(s[3:], ";") {
+		if field == "" {
+			continue
+		}
+		kv := strings.SplitN(field, ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key, val := kv[0], kv[1]
+
+		switch key {
+		case "thread":
+			ev.ThreadID = val
+		case "threads":
+			ev.Threads = strings.Split(val, ",")
+		case "reason":
+			ev.Reason = val
+		case "watch", "rwatch", "awatch":
+			if addr, err := strconv.ParseUint(val, 16, 64); err == nil {
+				ev.WatchpointAddr = &Address{value: addr}
+			}
+			if ev.Reason == "" {
+				ev.Reason = key
+			}
+		case "swbreak":
+			// Bare flag field (empty value) sent by plain gdbserver/rr
+			// stubs instead of debugserver's "reason:breakpoint".
+			if ev.Reason == "" {
+				ev.Reason = "breakpoint"
+			}
+		case "hwbreak":
+			if ev.Reason == "" {
+				ev.Reason = "hwbreak"
+			}
+		case "replaylog":
+			// Reported by rr-compatible stubs when reverse execution has
+			// hit the beginning or end of the recorded trace (val is
+			// "begin" or "end"), alongside the regular stop reason.
+			if ev.Reason == "" {
+				ev.Reason = "replaylog:" + val
+			} else {
+				ev.Reason += ";replaylog:" + val
+			}
+		default:
+			// "xx:yy" register pairs: xx is the hex register number, yy is
+			// its hex-encoded, target-byte-order (little-endian) value.
+			if _, err := strconv.ParseUint(key, 16, 32); err == nil {
+				if regVal, err := decodeLittleEndianHex(val); err == nil {
+					ev.Registers[key] = regVal
+				}
+			}
+		}
+	}
+
+	if ev.Reason == "" {
+		ev.Reason = "signal"
+	}
+
+	return ev
+}
+
+// decodeLittleEndianHex decodes a hex-encoded, little-endian value (as sent
+// in T-packet "regnum:value;" pairs) into a uint64.
+func decodeLittleEndianHex(hexStr string) (uint64, error) {
+	raw, err := hex.DecodeString(hexStr)
+	if err != nil {
+		return 0, err
+	}
+	var val uint64
+	for i := len(raw) - 1; i >= 0; i-- {
+		val = val<<8 | uint64(raw[i])
+	}
+	return val, nil
+}
@@ -0,0 +1,70 @@
+package golldb
+
+import (
+	"debug/macho"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// loadedLibrariesReply mirrors the shape of a jGetLoadedDynamicLibrariesInfos
+// reply: a list of every image debugserver has loaded into the inferior.
+type loadedLibrariesReply struct {
+	Images []loadedImage `json:"images"`
+}
+
+type loadedImage struct {
+	Pathname   string         `json:"pathname"`
+	MachHeader machHeaderInfo `json:"mach_header"`
+}
+
+type machHeaderInfo struct {
+	Filetype uint32 `json:"filetype"`
+}
+
+// ExecutablePath returns the on-disk path of the attached inferior's main
+// executable, as resolved by resolveExecutablePath during Attach.
+func (l *LLDB) ExecutablePath() string {
+	return l.execPath
+}
+
+// pidFromThreadID extracts the pid from a "pPID.TID" style thread ID, or
+// returns "" if threadID isn't in that form (i.e. multiprocess is inactive).
+func pidFromThreadID(threadID string) string {
+	if !strings.HasPrefix(threadID, "p") {
+		return ""
+	}
+	pid, _, found := strings.Cut(threadID[1:], ".")
+	if !found {
+		return ""
+	}
+	return pid
+}
+
+// resolveExecutablePath discovers the on-disk path of the attached
+// inferior's main executable and caches it on l. debugserver doesn't
+// implement qXfer:exec-file:read or return a path from qProcessInfo, so we
+// ask it for every loaded image and pick out the one flagged MH_EXECUTE;
+// gdbserver/lldb-server on Linux support qXfer:exec-file:read directly, so
+// that's used as a fallback.
+func (l *LLDB) resolveExecutablePath(pid string) error {
+	repl, err := l.execSimple(`jGetLoadedDynamicLibrariesInfos:{"fetch_all_solibs":true}`)
+	if err == nil {
+		var reply loadedLibrariesReply
+		if jsonErr := json.Unmarshal(repl, &reply); jsonErr == nil {
+			for _, image := range reply.Images {
+				if macho.Type(image.MachHeader.Filetype) == macho.TypeExec {
+					l.execPath = image.Pathname
+					return nil
+				}
+			}
+		}
+	}
+
+	data, err := l.readXfer("exec-file", pid)
+	if err != nil {
+		return fmt.Errorf("golldb: could not resolve executable path: %w", err)
+	}
+	l.execPath = string(data)
+	return nil
+}
@@ -0,0 +1,117 @@
+package golldb
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// connectRetryInterval is how long LaunchLLDBServer waits between connect
+// attempts while the stub is coming up.
+const connectRetryInterval = 200 * time.Millisecond
+
+// LaunchOptions configures LaunchLLDBServer.
+type LaunchOptions struct {
+	// Port to listen on; 0 picks a free port.
+	Port int
+	// Args are extra arguments passed to the stub before the listen
+	// address, e.g. []string{"gdbserver"} to invoke "lldb-server gdbserver".
+	Args []string
+	// Output, if set, receives the stub's stdout and stderr, useful for
+	// debugging a stub that never comes up.
+	Output io.Writer
+}
+
+// LaunchLLDBServer starts stubPath (debugserver on macOS, "lldb-server
+// gdbserver" or "gdbserver" on Linux) as a child process listening on
+// 127.0.0.1 and connects to it once it comes up. Unlike NewLLDBServer, which
+// dials once, it retries the connect for as long as the child is alive rather
+// than for a fixed attempt count: on macOS, debugserver can block for an arbitrary amount of
+// time waiting on its authorization prompt, so a fixed timeout would give up
+// too early. This mirrors how delve's gdbserial package launches stubs.
+func LaunchLLDBServer(stubPath string, opts LaunchOptions) (*LLDB, error) {
+	port := opts.Port
+	if port == 0 {
+		p, err := pickFreePort()
+		if err != nil {
+			return nil, err
+		}
+		port = p
+	}
+	address := fmt.Sprintf("127.0.0.1:%d", port)
+
+	args := append(append([]string{}, opts.Args...), address)
+	cmd := exec.Command(stubPath, args...)
+
+	var stderr bytes.Buffer
+	if opts.Output != nil {
+		cmd.Stdout = opts.Output
+		cmd.Stderr = io.MultiWriter(opts.Output, &stderr)
+	} else {
+		cmd.Stderr = &stderr
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	// cmd.Stdout/cmd.Stderr are non-*os.File writers, so exec.Cmd copies
+	// their output on background goroutines that only cmd.Wait() (not the
+	// raw os.Process.Wait()) synchronizes with. Using cmd.Wait() here
+	// ensures those copies have finished - and stderr is fully populated -
+	// before we read it below.
+	done := make(chan *os.ProcessState, 1)
+	go func() {
+		cmd.Wait()
+		done <- cmd.ProcessState
+	}()
+
+	conn, err := dialUntilReadyOrExited(address, done)
+	if err != nil {
+		return nil, fmt.Errorf("golldb: %w; stub stderr: %s", err, stderr.String())
+	}
+
+	lldb, err := newLLDBFromConn(conn)
+	if err != nil {
+		cmd.Process.Kill()
+		return nil, err
+	}
+
+	lldb.process = cmd.Process
+	lldb.exited = done
+
+	return lldb, nil
+}
+
+// dialUntilReadyOrExited repeatedly dials address until either a connection
+// succeeds or done reports that the child process has exited.
+func dialUntilReadyOrExited(address string, done <-chan *os.ProcessState) (net.Conn, error) {
+	for {
+		select {
+		case state := <-done:
+			return nil, fmt.Errorf("stub exited before accepting a connection (%v)", state)
+		default:
+		}
+
+		conn, err := net.Dial("tcp", address)
+		if err == nil {
+			return conn, nil
+		}
+
+		time.Sleep(connectRetryInterval)
+	}
+}
+
+// pickFreePort asks the OS for an unused TCP port on 127.0.0.1.
+func pickFreePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
@@ -1,22 +1,80 @@
 package golldb
 
 import (
+	"bufio"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net"
+	"os"
 	"strconv"
-	"strings"
 	"sync"
 )
 
 type LLDB struct {
 	conn   net.Conn
+	reader *bufio.Reader
 	target string
+	// execPath is the on-disk path of the attached inferior's main
+	// executable, resolved by Attach; see ExecutablePath.
+	execPath string
+
+	// noAckMode is true once the stub has confirmed QStartNoAckMode, at
+	// which point packet acknowledgements are suppressed in both directions.
+	noAckMode bool
+	// maxAttempts bounds how many times a packet is (re)sent before
+	// send/receive give up; see SetMaxAttempts.
+	maxAttempts int
+	// packetSize is the maximum payload size the stub accepts, 2048 until
+	// qSupported negotiation says otherwise.
+	packetSize int
+
+	// features holds the raw qSupported feature map as negotiated with the
+	// stub; see SupportsFeature.
+	features map[string]string
+	// hostInfo caches the qHostInfo/qGDBServerVersion key-value pairs; see
+	// HostInfo.
+	hostInfo map[string]string
+	// registers and registersByName cache the register layout parsed from
+	// target.xml; see fetchTargetXML, ReadRegisters, ReadRegister.
+	registers       []RegisterInfo
+	registersByName map[string]RegisterInfo
+
+	multiprocess           bool
+	threadSuffixSupported  bool
+	vContSupported         bool
+	swbreak                bool
+	hwbreak                bool
+	listThreadsInStopReply bool
+
+	// events is where StopEvent values are published as the stub reports
+	// the target stopping; see Events.
+	events chan StopEvent
+	// stdoutWriter receives decoded O-packet bytes (inferior stdout) while
+	// the target is running; see SetStdoutWriter.
+	stdoutWriter io.Writer
+	// waitStop, when non-nil, is closed by the next stop-reply the event
+	// loop observes; Interrupt uses it to block until the break lands.
+	waitStop chan struct{}
+
+	// process and exited are only set when the stub was started with
+	// LaunchLLDBServer; Close uses them to kill and reap it.
+	process *os.Process
+	exited  <-chan *os.ProcessState
+
+	// direction selects forward vs. backward execution for Continue/Step;
+	// see SetDirection.
+	direction Direction
+
 	*sync.Mutex
 }
 
+// ErrNotAttached is returned by Run, Continue, Step, ReverseContinue, and
+// ReverseStep when called before a target has been created or attached to.
+var ErrNotAttached = errors.New("golldb: target is not created or not attached to process")
+
 type Address struct {
 	value uint64
 }
@@ -28,18 +86,46 @@ func (a *Address) String() string {
 // NewLLDBServer returns new instance of LLDB struct that is used to interact
 // with remote gdbserver/lldb-server
 func NewLLDBServer(ip, port string) (*LLDB, error) {
-	address := fmt.Sprintf("%s:%s", ip, port)
-	conn, err := net.Dial("tcp", address)
+	conn, err := net.Dial("tcp", fmt.Sprintf("%s:%s", ip, port))
 	if err != nil {
 		return nil, err
 	}
-	buffer := make([]byte, 24)
-	conn.Write([]byte("$QStartNoAckMode#b0"))
-	conn.Read(buffer)
-	conn.Write([]byte("+"))
+	return newLLDBFromConn(conn)
+}
 
+// newLLDBFromConn wraps an already-established connection to a
+// gdbserver/lldb-server stub, running the no-ack handshake and feature
+// negotiation shared by NewLLDBServer and LaunchLLDBServer.
+func newLLDBFromConn(conn net.Conn) (*LLDB, error) {
 	lldb := &LLDB{
-		conn: conn,
+		conn:        conn,
+		reader:      bufio.NewReader(conn),
+		maxAttempts: defaultMaxAttempts,
+		packetSize:  defaultPacketSize,
+		events:      make(chan StopEvent, 16),
+		Mutex:       &sync.Mutex{},
+	}
+
+	if err := lldb.sendPacket("QStartNoAckMode"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if _, err := lldb.recvPacket(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	lldb.noAckMode = true
+
+	if err := lldb.negotiateFeatures(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if lldb.SupportsFeature("qXfer:features:read") {
+		if err := lldb.fetchTargetXML(); err != nil {
+			conn.Close()
+			return nil, err
+		}
 	}
 
 	return lldb, nil
@@ -47,54 +133,83 @@ func NewLLDBServer(ip, port string) (*LLDB, error) {
 
 // GetThreads returns information about the threads running.
 func (l *LLDB) GetThreads() (map[string]any, error) {
-	msg := "jThreadsInfo"
-	repl := string(l.execSimple(msg))
-	repl = strings.TrimLeft(repl, "$")
-	repl = strings.TrimRight(repl, "#00")
+	repl, err := l.execSimple("jThreadsInfo")
+	if err != nil {
+		return nil, err
+	}
 
 	mp := make(map[string]any)
-	json.Unmarshal([]byte(repl), &mp)
+	if err := json.Unmarshal(repl, &mp); err != nil {
+		return nil, err
+	}
 	return mp, nil
 }
 
-// Interrupt interrupts the running binary as if it has been sent CTRL+C
+// Interrupt interrupts the running binary as if it has been sent CTRL+C and
+// blocks until the resulting stop event has been observed. The event itself
+// is still delivered to callers reading Events().
 func (l *LLDB) Interrupt() error {
-	l.execSimple("vCtrlC")
+	l.Lock()
+	wait := make(chan struct{})
+	l.waitStop = wait
+	l.Unlock()
+
+	var err error
+	if l.multiprocess {
+		err = l.sendPacket("vCtrlC")
+	} else {
+		_, err = l.conn.Write([]byte{0x03})
+	}
+	if err != nil {
+		return err
+	}
+
+	<-wait
 	return nil
 }
 
-// Close closes underlying connection to the gdbserver/lldb-server
+// Close closes underlying connection to the gdbserver/lldb-server. If the
+// stub was started with LaunchLLDBServer, its process is also killed and
+// reaped.
 func (l *LLDB) Close() error {
 	if l.target != "" {
 		if err := l.Detach(); err != nil {
 			return err
 		}
 	}
-	return l.conn.Close()
+
+	err := l.conn.Close()
+
+	if l.process != nil {
+		l.process.Kill()
+		<-l.exited
+	}
+
+	return err
 }
 
 // SetStdout sets stdout for the target that we will create.
 func (l *LLDB) SetStdout(path string) error {
 	stdout := hex.EncodeToString([]byte(path))
 	msg := fmt.Sprintf("QSetSTDOUT:%s", stdout)
-	l.execSimple(msg)
-	return nil
+	_, err := l.execSimple(msg)
+	return err
 }
 
 // SetStdin sets stdin for the target that we will create.
 func (l *LLDB) SetStdin(path string) error {
 	stdin := hex.EncodeToString([]byte(path))
 	msg := fmt.Sprintf("QSetSTDIN:%s", stdin)
-	l.execSimple(msg)
-	return nil
+	_, err := l.execSimple(msg)
+	return err
 }
 
 // SetStderr sets stderr for the target that we will create.
 func (l *LLDB) SetStderr(path string) error {
 	stderr := hex.EncodeToString([]byte(path))
 	msg := fmt.Sprintf("QSetSTDERR:%s", stderr)
-	l.execSimple(msg)
-	return nil
+	_, err := l.execSimple(msg)
+	return err
 }
 
 // SetEnv sets environment variables for the target that we will create.
@@ -127,35 +242,88 @@ func (l *LLDB) Create(target string, argv ...string) error {
 	}
 
 	l.target = target
-	l.execSimple(msg)
-	return nil
+	_, err := l.execSimple(msg)
+	return err
 }
 
-// Run runs the target previously created.
+// Run runs the target previously created. It returns as soon as the
+// continue packet is sent; callers should select on Events() for the
+// resulting StopEvent.
 func (l *LLDB) Run() error {
 	if l.target == "" {
-		return errors.New("cannot run; target is not created")
+		return ErrNotAttached
 	}
-	l.execSimple("c")
-	return nil
+	return l.goContinue()
 }
 
-// Continue continues the execution of the debugged target.
+// Continue continues the execution of the debugged target, forward or
+// backward depending on SetDirection. It returns as soon as the continue
+// packet is sent; callers should select on Events() for the resulting
+// StopEvent.
 func (l *LLDB) Continue() error {
 	if l.target == "" {
-		return errors.New("cannot continue; target is not created or not attach to process")
+		return ErrNotAttached
+	}
+	if l.direction == DirectionBackward {
+		return l.ReverseContinue()
+	}
+	return l.goContinue()
+}
+
+// Step single-steps thread (or the current thread if empty) by one
+// instruction, forward or backward depending on SetDirection. It returns as
+// soon as the step packet is sent; callers should select on Events() for
+// the resulting StopEvent.
+func (l *LLDB) Step(thread string) error {
+	if l.target == "" {
+		return ErrNotAttached
+	}
+	if l.direction == DirectionBackward {
+		return l.ReverseStep(thread)
+	}
+
+	msg := "s"
+	switch {
+	case l.vContSupported && thread != "":
+		msg = "vCont;s:" + thread
+	case l.vContSupported:
+		msg = "vCont;s"
+	case thread != "" && l.threadSuffixSupported:
+		msg = "s;thread:" + thread
+	}
+	if err := l.sendPacket(msg); err != nil {
+		return err
+	}
+	go l.runUntilStop()
+	return nil
+}
+
+// goContinue sends the continue packet and starts the background reader
+// that waits for the matching stop-reply, preferring "vCont;c" over the
+// plain "c" packet when the stub advertised vContSupported.
+func (l *LLDB) goContinue() error {
+	msg := "c"
+	if l.vContSupported {
+		msg = "vCont;c"
+	}
+	if err := l.sendPacket(msg); err != nil {
+		return err
 	}
-	l.execSimple("c")
+	go l.runUntilStop()
 	return nil
 }
 
 // Allocate will allocate size bytes with the permissions passed.
 func (l *LLDB) Allocate(size int, permissions string) (*Address, error) {
 	msg := "_M" + strconv.Itoa(size) + "," + permissions
-	res := string(l.execSimple(msg))
-	res = strings.Replace(res, "$", "", -1)
-	res = strings.Replace(res, "#00", "", -1)
-	addr, _ := strconv.ParseUint(res, 16, 64)
+	res, err := l.execSimple(msg)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := strconv.ParseUint(string(res), 16, 64)
+	if err != nil {
+		return nil, err
+	}
 	return &Address{
 		value: addr,
 	}, nil
@@ -170,36 +338,53 @@ func (l *LLDB) WriteAtAddress(addr *Address, data []byte) error {
 	msg += strconv.Itoa(len(encoded))
 	msg += ":"
 	msg += encoded
-	l.execSimple(msg)
-	return nil
+	_, err := l.execSimple(msg)
+	return err
 }
 
-// Attach attaches to the running program by name.
+// Attach attaches to the running program by name and, best-effort, resolves
+// the on-disk path of its main executable; see ExecutablePath. Failure to
+// resolve the executable path does not fail the attach: by the time it runs,
+// vAttachName has already succeeded and the stub considers us attached, so
+// returning an error here would leave the target attached with no way for
+// the caller to Close/Detach it.
 func (l *LLDB) Attach(name string) error {
 	l.target = name
 	msg := "vAttachName;" + hex.EncodeToString([]byte(name))
-	l.execSimple(msg)
+	repl, err := l.execSimple(msg)
+	if err != nil {
+		return err
+	}
+
+	var pid string
+	if len(repl) > 0 {
+		switch repl[0] {
+		case 'T', 'S', 'W', 'X':
+			pid = pidFromThreadID(parseStopReply(repl).ThreadID)
+		}
+	}
+
+	l.resolveExecutablePath(pid)
 	return nil
 }
 
 // Detach detaches from the debugger program.
 func (l *LLDB) Detach() error {
-	l.execSimple("D")
-	return nil
+	_, err := l.execSimple("D")
+	return err
 }
 
 // SaveRegisters saves current snapshot of the registers.
 func (l *LLDB) SaveRegisters() error {
-	l.execSimple("QSaveRegisterState")
-	return nil
+	_, err := l.execSimple("QSaveRegisterState")
+	return err
 }
 
-func (l *LLDB) execSimple(msg string) []byte {
-	buffer := make([]byte, 2048)
-	content := "$"
-	content += msg
-	content += "#00"
-	l.conn.Write([]byte(content))
-	read, _ := l.conn.Read(buffer)
-	return buffer[:read]
+// execSimple sends msg as a packet and returns the stub's unescaped reply
+// payload, framing, checksumming and (re)transmitting as required.
+func (l *LLDB) execSimple(msg string) ([]byte, error) {
+	if err := l.sendPacket(msg); err != nil {
+		return nil, err
+	}
+	return l.recvPacket()
 }
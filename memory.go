@@ -0,0 +1,50 @@
+package golldb
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// ReadMemory reads n bytes starting at addr, issuing as many "m" packets as
+// needed to stay within the negotiated packet size.
+func (l *LLDB) ReadMemory(addr *Address, n int) ([]byte, error) {
+	out := make([]byte, 0, n)
+	cur := addr.value
+	remaining := n
+
+	for remaining > 0 {
+		chunk := remaining
+		if max := l.maxMemoryChunk(); chunk > max {
+			chunk = max
+		}
+
+		repl, err := l.execSimple(fmt.Sprintf("m%x,%x", cur, chunk))
+		if err != nil {
+			return nil, err
+		}
+		decoded, err := hex.DecodeString(string(repl))
+		if err != nil {
+			return nil, err
+		}
+		if len(decoded) == 0 {
+			return nil, fmt.Errorf("golldb: stub returned no data reading memory at %x", cur)
+		}
+
+		out = append(out, decoded...)
+		cur += uint64(len(decoded))
+		remaining -= len(decoded)
+	}
+
+	return out, nil
+}
+
+// maxMemoryChunk returns how many bytes can be requested per "m" packet: the
+// negotiated packet size, halved to leave room for hex encoding (two hex
+// digits per byte).
+func (l *LLDB) maxMemoryChunk() int {
+	size := l.packetSize
+	if size <= 0 {
+		size = defaultPacketSize
+	}
+	return size / 2
+}
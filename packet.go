@@ -0,0 +1,151 @@
+package golldb
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// defaultMaxAttempts is how many times we retry sending or receiving a
+// packet before giving up, absent an explicit call to SetMaxAttempts.
+const defaultMaxAttempts = 3
+
+// defaultPacketSize is the packet size assumed before qSupported negotiation
+// has told us what the stub can actually handle.
+const defaultPacketSize = 2048
+
+// checksum computes the GDB remote serial protocol checksum: the 8-bit
+// modulo-256 sum of payload.
+func checksum(payload []byte) byte {
+	var sum byte
+	for _, b := range payload {
+		sum += b
+	}
+	return sum
+}
+
+// escapePayload escapes the bytes that are special to the wire format using
+// the `}`-XOR-0x20 escape.
+func escapePayload(payload []byte) []byte {
+	var buf bytes.Buffer
+	for _, b := range payload {
+		switch b {
+		case '#', '$', '}', '*':
+			buf.WriteByte('}')
+			buf.WriteByte(b ^ 0x20)
+		default:
+			buf.WriteByte(b)
+		}
+	}
+	return buf.Bytes()
+}
+
+// unescapePayload reverses escapePayload and additionally expands run-length
+// encoded runs of the form "<char>*<n>", where n-29 gives the number of
+// additional repeats of <char>.
+func unescapePayload(payload []byte) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < len(payload); i++ {
+		b := payload[i]
+		switch {
+		case b == '}' && i+1 < len(payload):
+			i++
+			buf.WriteByte(payload[i] ^ 0x20)
+		case b == '*' && i+1 < len(payload) && buf.Len() > 0:
+			i++
+			count := int(payload[i]) - 29
+			last := buf.Bytes()[buf.Len()-1]
+			for j := 0; j < count; j++ {
+				buf.WriteByte(last)
+			}
+		default:
+			buf.WriteByte(b)
+		}
+	}
+	return buf.Bytes()
+}
+
+// attempts returns the configured retry budget, defaulting it if unset.
+func (l *LLDB) attempts() int {
+	if l.maxAttempts <= 0 {
+		return defaultMaxAttempts
+	}
+	return l.maxAttempts
+}
+
+// SetMaxAttempts configures how many times in total a packet is sent (or a
+// reply read) before send/receive give up with an error.
+func (l *LLDB) SetMaxAttempts(n int) {
+	l.maxAttempts = n
+}
+
+// sendPacket frames msg as a GDB remote serial protocol packet and writes it
+// to the connection, retrying until the stub acknowledges it or the
+// configured attempt budget is exhausted. Acks are skipped once no-ack mode
+// has been negotiated.
+func (l *LLDB) sendPacket(msg string) error {
+	payload := escapePayload([]byte(msg))
+	if max := l.packetSize; max > 0 && len(payload) > max {
+		return fmt.Errorf("golldb: packet payload of %d bytes exceeds negotiated PacketSize of %d", len(payload), max)
+	}
+	packet := fmt.Sprintf("$%s#%02x", payload, checksum(payload))
+
+	for attempt := 0; attempt < l.attempts(); attempt++ {
+		if _, err := l.conn.Write([]byte(packet)); err != nil {
+			return err
+		}
+		if l.noAckMode {
+			return nil
+		}
+
+		ack, err := l.reader.ReadByte()
+		if err != nil {
+			return err
+		}
+		if ack == '+' {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("golldb: no ack for packet %q after %d attempts", msg, l.attempts())
+}
+
+// recvPacket reads a single packet from the stub, verifying its checksum and
+// requesting retransmission on mismatch. Acks are skipped once no-ack mode
+// has been negotiated.
+func (l *LLDB) recvPacket() ([]byte, error) {
+	for attempt := 0; attempt < l.attempts(); attempt++ {
+		if _, err := l.reader.ReadBytes('$'); err != nil {
+			return nil, err
+		}
+		raw, err := l.reader.ReadBytes('#')
+		if err != nil {
+			return nil, err
+		}
+		raw = raw[:len(raw)-1]
+
+		hexSum := make([]byte, 2)
+		if _, err := io.ReadFull(l.reader, hexSum); err != nil {
+			return nil, err
+		}
+		want, err := strconv.ParseUint(string(hexSum), 16, 8)
+		if err != nil {
+			return nil, err
+		}
+
+		if checksum(raw) != byte(want) {
+			if !l.noAckMode {
+				l.conn.Write([]byte("-"))
+			}
+			continue
+		}
+
+		if !l.noAckMode {
+			l.conn.Write([]byte("+"))
+		}
+		return unescapePayload(raw), nil
+	}
+
+	return nil, fmt.Errorf("golldb: checksum mismatch after %d attempts", l.attempts())
+}
@@ -0,0 +1,100 @@
+package golldb
+
+import (
+	"strconv"
+	"strings"
+)
+
+// qSupportedRequest is sent once, right after the no-ack handshake, to
+// advertise the features this client understands and learn what the stub
+// supports in return.
+const qSupportedRequest = "qSupported:multiprocess+;swbreak+;hwbreak+;xmlRegisters=i386,arm,mips;vContSupported+;QThreadSuffixSupported+;QListThreadsInStopReply+;qXfer:features:read+;ReverseStep+;ReverseContinue+"
+
+// parseQSupported parses a semicolon-separated qSupported reply such as
+// "PacketSize=4000;multiprocess+;swbreak+;hwbreak+" into a map keyed by
+// feature name. Values are "+" (supported), "-" (unsupported), "?" (might be
+// supported), or the string after "=" for key=value features such as
+// PacketSize.
+func parseQSupported(reply string) map[string]string {
+	features := make(map[string]string)
+	for _, part := range strings.Split(reply, ";") {
+		if part == "" {
+			continue
+		}
+		if eq := strings.IndexByte(part, '='); eq != -1 {
+			features[part[:eq]] = part[eq+1:]
+			continue
+		}
+		switch last := part[len(part)-1]; last {
+		case '+', '-', '?':
+			features[part[:len(part)-1]] = string(last)
+		default:
+			features[part] = ""
+		}
+	}
+	return features
+}
+
+// parseHostInfoReply parses the colon/semicolon key-value replies used by
+// qHostInfo and qGDBServerVersion, e.g. "cputype:12;ostype:macosx;ptrsize:8;".
+func parseHostInfoReply(reply string) map[string]string {
+	info := make(map[string]string)
+	for _, part := range strings.Split(reply, ";") {
+		kv := strings.SplitN(part, ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		info[kv[0]] = kv[1]
+	}
+	return info
+}
+
+// negotiateFeatures sends qSupported and caches the stub's capabilities on
+// l, then probes qHostInfo/qGDBServerVersion for host characteristics. It is
+// called once, right after the no-ack handshake completes.
+func (l *LLDB) negotiateFeatures() error {
+	repl, err := l.execSimple(qSupportedRequest)
+	if err != nil {
+		return err
+	}
+
+	l.features = parseQSupported(string(repl))
+	if size, ok := l.features["PacketSize"]; ok {
+		if n, err := strconv.ParseInt(size, 16, 32); err == nil {
+			l.packetSize = int(n)
+		}
+	}
+	l.multiprocess = l.features["multiprocess"] == "+"
+	l.threadSuffixSupported = l.features["QThreadSuffixSupported"] == "+"
+	l.vContSupported = l.features["vContSupported"] == "+"
+	l.swbreak = l.features["swbreak"] == "+"
+	l.hwbreak = l.features["hwbreak"] == "+"
+	l.listThreadsInStopReply = l.features["QListThreadsInStopReply"] == "+"
+
+	l.hostInfo = make(map[string]string)
+	if repl, err := l.execSimple("qHostInfo"); err == nil {
+		for k, v := range parseHostInfoReply(string(repl)) {
+			l.hostInfo[k] = v
+		}
+	}
+	if repl, err := l.execSimple("qGDBServerVersion"); err == nil {
+		for k, v := range parseHostInfoReply(string(repl)) {
+			l.hostInfo[k] = v
+		}
+	}
+
+	return nil
+}
+
+// HostInfo returns the stub's reported host characteristics (cputype,
+// ostype, endian, ptrsize, triple, ...), gathered via qHostInfo and
+// qGDBServerVersion during connection setup.
+func (l *LLDB) HostInfo() map[string]string {
+	return l.hostInfo
+}
+
+// SupportsFeature reports whether the stub advertised the named qSupported
+// feature as "+" during negotiation.
+func (l *LLDB) SupportsFeature(name string) bool {
+	return l.features[name] == "+"
+}
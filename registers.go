@@ -0,0 +1,174 @@
+package golldb
+
+import (
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+)
+
+// RegisterInfo describes where a single register lives in the "g"-packet
+// hex dump, as parsed from the stub's target.xml.
+type RegisterInfo struct {
+	Name   string
+	Number int
+	Offset int // byte offset into the "g" packet dump
+	Size   int // size in bytes
+}
+
+// targetXMLDoc mirrors the handful of target.xml elements we care about;
+// see the GDB remote protocol "Target Description Format" documentation.
+type targetXMLDoc struct {
+	XMLName  xml.Name        `xml:"target"`
+	Features []targetXMLFeat `xml:"feature"`
+	Includes []targetXMLIncl `xml:"include"`
+}
+
+type targetXMLFeat struct {
+	Name string         `xml:"name,attr"`
+	Regs []targetXMLReg `xml:"reg"`
+}
+
+type targetXMLIncl struct {
+	Href string `xml:"href,attr"`
+}
+
+type targetXMLReg struct {
+	Name    string `xml:"name,attr"`
+	BitSize int    `xml:"bitsize,attr"`
+	Regnum  *int   `xml:"regnum,attr"`
+	Offset  *int   `xml:"offset,attr"`
+}
+
+// collectFeatures parses a target description XML document (target.xml or
+// a file it xi:include's) and appends its <feature> elements to features,
+// then recurses into any xi:include'd files, fetching each via
+// qXfer:features:read. visited guards against include cycles.
+func (l *LLDB) collectFeatures(data []byte, visited map[string]bool, features *[]targetXMLFeat) error {
+	var doc targetXMLDoc
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	*features = append(*features, doc.Features...)
+
+	for _, include := range doc.Includes {
+		if include.Href == "" || visited[include.Href] {
+			continue
+		}
+		visited[include.Href] = true
+
+		included, err := l.readXfer("features", include.Href)
+		if err != nil {
+			return err
+		}
+		if err := l.collectFeatures(included, visited, features); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fetchTargetXML reads and parses qXfer:features:read:target.xml, resolving
+// any xi:include'd files (x86_64 stubs commonly split registers across a
+// core/sse/fpu set of included documents), and caches the resulting
+// register layout on l. It is called once, during connection setup, when
+// the stub advertises qXfer:features:read support.
+func (l *LLDB) fetchTargetXML() error {
+	data, err := l.readXfer("features", "target.xml")
+	if err != nil {
+		return err
+	}
+
+	var features []targetXMLFeat
+	if err := l.collectFeatures(data, map[string]bool{"target.xml": true}, &features); err != nil {
+		return err
+	}
+
+	l.registers = nil
+	l.registersByName = make(map[string]RegisterInfo)
+
+	offset, num := 0, 0
+	for _, feature := range features {
+		for _, reg := range feature.Regs {
+			size := reg.BitSize / 8
+
+			regNum := num
+			if reg.Regnum != nil {
+				regNum = *reg.Regnum
+			}
+			regOffset := offset
+			if reg.Offset != nil {
+				regOffset = *reg.Offset
+			}
+
+			info := RegisterInfo{
+				Name:   reg.Name,
+				Number: regNum,
+				Offset: regOffset,
+				Size:   size,
+			}
+			l.registers = append(l.registers, info)
+			l.registersByName[reg.Name] = info
+
+			offset = regOffset + size
+			num = regNum + 1
+		}
+	}
+
+	return nil
+}
+
+// ReadRegisters reads the full register set for thread (or the stub's
+// current thread if empty), returning each register's raw bytes keyed by
+// name using the offsets/sizes parsed from target.xml.
+func (l *LLDB) ReadRegisters(thread string) (map[string][]byte, error) {
+	msg := "g"
+	if thread != "" && l.threadSuffixSupported {
+		msg += ";thread:" + thread
+	}
+
+	repl, err := l.execSimple(msg)
+	if err != nil {
+		return nil, err
+	}
+	dump, err := hex.DecodeString(string(repl))
+	if err != nil {
+		return nil, err
+	}
+
+	regs := make(map[string][]byte, len(l.registers))
+	for _, info := range l.registers {
+		if info.Offset+info.Size > len(dump) {
+			continue
+		}
+		regs[info.Name] = dump[info.Offset : info.Offset+info.Size]
+	}
+	return regs, nil
+}
+
+// ReadRegister reads a single register by name via the "p" packet.
+func (l *LLDB) ReadRegister(name string) ([]byte, error) {
+	info, ok := l.registersByName[name]
+	if !ok {
+		return nil, fmt.Errorf("golldb: unknown register %q", name)
+	}
+
+	repl, err := l.execSimple(fmt.Sprintf("p%x", info.Number))
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(string(repl))
+}
+
+// WriteRegister writes val to the named register via the "P" packet.
+func (l *LLDB) WriteRegister(name string, val []byte) error {
+	info, ok := l.registersByName[name]
+	if !ok {
+		return fmt.Errorf("golldb: unknown register %q", name)
+	}
+
+	msg := fmt.Sprintf("P%x=%s", info.Number, hex.EncodeToString(val))
+	_, err := l.execSimple(msg)
+	return err
+}
@@ -0,0 +1,60 @@
+package golldb
+
+import "errors"
+
+// ErrReverseNotSupported is returned by ReverseContinue/ReverseStep, and by
+// Continue/Step when SetDirection(DirectionBackward) is active, when the
+// stub didn't advertise the corresponding qSupported feature.
+var ErrReverseNotSupported = errors.New("golldb: stub does not support reverse execution")
+
+// Direction selects whether Continue/Step run the target forward or
+// backward, for stubs that support reverse execution (mozilla rr,
+// gdbserver in record mode).
+type Direction int
+
+const (
+	DirectionForward Direction = iota
+	DirectionBackward
+)
+
+// SetDirection selects the execution direction used by subsequent Continue
+// and Step calls.
+func (l *LLDB) SetDirection(d Direction) {
+	l.direction = d
+}
+
+// ReverseContinue runs the target backward until a breakpoint, watchpoint,
+// or the beginning of the recorded trace is reached, via the "bc" packet.
+func (l *LLDB) ReverseContinue() error {
+	if l.target == "" {
+		return ErrNotAttached
+	}
+	if !l.SupportsFeature("ReverseContinue") {
+		return ErrReverseNotSupported
+	}
+	if err := l.sendPacket("bc"); err != nil {
+		return err
+	}
+	go l.runUntilStop()
+	return nil
+}
+
+// ReverseStep steps thread (or the current thread if empty) backward by one
+// instruction, via the "bs" packet.
+func (l *LLDB) ReverseStep(thread string) error {
+	if l.target == "" {
+		return ErrNotAttached
+	}
+	if !l.SupportsFeature("ReverseStep") {
+		return ErrReverseNotSupported
+	}
+	msg := "bs"
+	if thread != "" && l.threadSuffixSupported {
+		msg += ";thread:" + thread
+	}
+	if err := l.sendPacket(msg); err != nil {
+		return err
+	}
+	go l.runUntilStop()
+	return nil
+}
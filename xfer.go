@@ -0,0 +1,43 @@
+package golldb
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// readXfer performs a paged qXfer read (used for target.xml, exec-file, and
+// other annex-based objects supported by the stub), assembling the
+// continuation reads into a single payload.
+func (l *LLDB) readXfer(object, annex string) ([]byte, error) {
+	var buf bytes.Buffer
+	offset := 0
+
+	for {
+		length := l.packetSize
+		if length <= 0 {
+			length = defaultPacketSize
+		}
+
+		msg := fmt.Sprintf("qXfer:%s:read:%s:%x,%x", object, annex, offset, length)
+		repl, err := l.execSimple(msg)
+		if err != nil {
+			return nil, err
+		}
+		if len(repl) == 0 {
+			return nil, fmt.Errorf("golldb: empty qXfer reply for %s:%s", object, annex)
+		}
+
+		kind, data := repl[0], repl[1:]
+		buf.Write(data)
+		offset += len(data)
+
+		switch kind {
+		case 'l':
+			return buf.Bytes(), nil
+		case 'm':
+			continue
+		default:
+			return nil, fmt.Errorf("golldb: unexpected qXfer reply kind %q for %s:%s", kind, object, annex)
+		}
+	}
+}